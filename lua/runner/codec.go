@@ -0,0 +1,227 @@
+package runner
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Content-type keys recognized by the built-in codecs.
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeForm = "application/x-www-form-urlencoded"
+	ContentTypeXML  = "application/xml"
+)
+
+// Codec marshals and unmarshals an HTTP body to and from the map[string]any
+// representation used for Lua tables, so REST can support content types
+// other than JSON.
+type Codec interface {
+	Marshal(map[string]any) ([]byte, error)
+	Unmarshal([]byte, *map[string]any) error
+}
+
+// defaultCodecs returns the codecs REST falls back to when none are passed
+// to NewRestRunner.
+func defaultCodecs() map[string]Codec {
+	return map[string]Codec{
+		ContentTypeJSON: jsonCodec{},
+		ContentTypeForm: formCodec{},
+		ContentTypeXML:  xmlCodec{},
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v map[string]any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(b []byte, v *map[string]any) error {
+	return json.Unmarshal(b, v)
+}
+
+type formCodec struct{}
+
+func (formCodec) Marshal(v map[string]any) ([]byte, error) {
+	values := url.Values{}
+	for k, val := range v {
+		if err := addFormValue(values, k, val); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(values.Encode()), nil
+}
+
+// addFormValue adds val to values under key, repeating the key for each
+// element of a []any (the standard way to encode a multi-value form field).
+// Nested objects have no well-defined form encoding, so they're rejected
+// instead of silently becoming Go's default %v rendering.
+func addFormValue(values url.Values, key string, val any) error {
+	switch t := val.(type) {
+	case []any:
+		for _, item := range t {
+			if err := addFormValue(values, key, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		return fmt.Errorf("form codec: field %q: nested objects are not supported", key)
+	default:
+		values.Add(key, fmt.Sprintf("%v", t))
+	}
+	return nil
+}
+
+func (formCodec) Unmarshal(b []byte, v *map[string]any) error {
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+
+	out := map[string]any{}
+	for k, vals := range values {
+		if len(vals) == 1 {
+			out[k] = vals[0]
+			continue
+		}
+		items := make([]any, len(vals))
+		for i, val := range vals {
+			items[i] = val
+		}
+		out[k] = items
+	}
+	*v = out
+	return nil
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v map[string]any) ([]byte, error) {
+	return xml.Marshal(mapToXMLElement("root", v))
+}
+
+func (xmlCodec) Unmarshal(b []byte, v *map[string]any) error {
+	var el xmlElement
+	if err := xml.Unmarshal(b, &el); err != nil {
+		return err
+	}
+	*v = el.toMap()
+	return nil
+}
+
+// xmlElement is a generic XML tree used to shuttle map[string]any values
+// through encoding/xml without a fixed schema.
+type xmlElement struct {
+	XMLName xml.Name
+	Content string       `xml:",chardata"`
+	Nodes   []xmlElement `xml:",any"`
+}
+
+func mapToXMLElement(name string, v map[string]any) xmlElement {
+	el := xmlElement{XMLName: xml.Name{Local: name}}
+	for k, val := range v {
+		el.Nodes = append(el.Nodes, valueToXMLElements(k, val)...)
+	}
+	return el
+}
+
+// valueToXMLElements renders val as one or more sibling elements named name.
+// A []any becomes repeated elements (the standard XML array encoding), so
+// round-tripping a Lua table with an array field doesn't silently fall back
+// to Go's %v rendering of the slice.
+func valueToXMLElements(name string, val any) []xmlElement {
+	switch t := val.(type) {
+	case map[string]any:
+		return []xmlElement{mapToXMLElement(name, t)}
+	case []any:
+		elements := make([]xmlElement, 0, len(t))
+		for _, item := range t {
+			elements = append(elements, valueToXMLElements(name, item)...)
+		}
+		return elements
+	default:
+		return []xmlElement{{XMLName: xml.Name{Local: name}, Content: fmt.Sprintf("%v", t)}}
+	}
+}
+
+func (el xmlElement) toMap() map[string]any {
+	counts := map[string]int{}
+	for _, child := range el.Nodes {
+		counts[child.XMLName.Local]++
+	}
+
+	m := map[string]any{}
+	for _, child := range el.Nodes {
+		name := child.XMLName.Local
+
+		var value any
+		if len(child.Nodes) > 0 {
+			value = child.toMap()
+		} else {
+			value = strings.TrimSpace(child.Content)
+		}
+
+		if counts[name] > 1 {
+			items, _ := m[name].([]any)
+			m[name] = append(items, value)
+			continue
+		}
+		m[name] = value
+	}
+	return m
+}
+
+// ProtobufCodec marshals and unmarshals a single protobuf message type via
+// protojson, so it can be registered under a content type such as
+// "application/protobuf;proto=<full message name>".
+type ProtobufCodec struct {
+	messageType protoreflect.MessageType
+}
+
+func NewProtobufCodec(messageType protoreflect.MessageType) *ProtobufCodec {
+	return &ProtobufCodec{messageType: messageType}
+}
+
+func (c *ProtobufCodec) Marshal(v map[string]any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := c.messageType.New().Interface()
+	if err := protojson.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(msg)
+}
+
+func (c *ProtobufCodec) Unmarshal(b []byte, v *map[string]any) error {
+	msg := c.messageType.New().Interface()
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return err
+	}
+
+	j, err := protojson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(j, v)
+}
+
+// contentTypeKey strips parameters (e.g. "; charset=utf-8") from a
+// Content-Type header value so it can be used as a codec lookup key.
+func contentTypeKey(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i != -1 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
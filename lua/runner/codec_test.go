@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	c := jsonCodec{}
+	want := map[string]any{"name": "alice", "age": float64(30)}
+
+	b, err := c.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := c.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFormCodecRoundTripScalar(t *testing.T) {
+	c := formCodec{}
+	want := map[string]any{"name": "alice", "age": "30"}
+
+	b, err := c.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := c.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFormCodecArrayRoundTrip(t *testing.T) {
+	c := formCodec{}
+	want := map[string]any{"tags": []any{"a", "b", "c"}}
+
+	b, err := c.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := c.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	tags, ok := got["tags"].([]any)
+	if !ok {
+		t.Fatalf("expected tags to decode as []any, got %T: %v", got["tags"], got["tags"])
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i].(string) < tags[j].(string) })
+	wantTags := []any{"a", "b", "c"}
+	if !reflect.DeepEqual(tags, wantTags) {
+		t.Fatalf("got tags %v, want %v", tags, wantTags)
+	}
+}
+
+func TestFormCodecRejectsNestedObject(t *testing.T) {
+	c := formCodec{}
+	if _, err := c.Marshal(map[string]any{"user": map[string]any{"name": "alice"}}); err == nil {
+		t.Fatal("expected Marshal to reject a nested object field")
+	}
+}
+
+// newUserMessageType builds a protoreflect.MessageType for a "codec.User{name,
+// age}" message from a hand-built FileDescriptorProto, so ProtobufCodec can
+// be exercised without depending on protoc/codegen.
+func newUserMessageType(t *testing.T) protoreflect.MessageType {
+	t.Helper()
+
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("codec.proto"),
+		Package: proto.String("codec"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("User"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("name"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						JsonName: proto.String("name"),
+					},
+					{
+						Name:     proto.String("age"),
+						Number:   proto.Int32(2),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+						JsonName: proto.String("age"),
+					},
+				},
+			},
+		},
+	}
+
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fdProto}})
+	if err != nil {
+		t.Fatalf("NewFiles: %v", err)
+	}
+
+	desc, err := files.FindDescriptorByName("codec.User")
+	if err != nil {
+		t.Fatalf("FindDescriptorByName: %v", err)
+	}
+
+	return dynamicpb.NewMessageType(desc.(protoreflect.MessageDescriptor))
+}
+
+func TestProtobufCodecRoundTrip(t *testing.T) {
+	c := NewProtobufCodec(newUserMessageType(t))
+	want := map[string]any{"name": "alice", "age": float64(30)}
+
+	b, err := c.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := c.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestXMLCodecRoundTripNested(t *testing.T) {
+	c := xmlCodec{}
+	want := map[string]any{
+		"name": "alice",
+		"address": map[string]any{
+			"city": "oslo",
+		},
+		"tags": []any{"a", "b"},
+	}
+
+	b, err := c.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := c.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if got["name"] != "alice" {
+		t.Fatalf("expected name == alice, got %v", got["name"])
+	}
+
+	addr, ok := got["address"].(map[string]any)
+	if !ok || addr["city"] != "oslo" {
+		t.Fatalf("expected address.city == oslo, got %v", got["address"])
+	}
+
+	tags, ok := got["tags"].([]any)
+	if !ok || len(tags) != 2 || tags[0] != "a" || tags[1] != "b" {
+		t.Fatalf("expected tags == [a b], got %v", got["tags"])
+	}
+}
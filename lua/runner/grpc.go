@@ -0,0 +1,349 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/nais/tester/lua/reporter"
+	"github.com/nais/tester/lua/spec"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const grpcBufSize = 1024 * 1024
+
+// GRPC is the gRPC factory counterpart to REST (see REST for the
+// factory/instance split rationale): the bufconn server/client and files
+// registry live here, metadata/status/response on the GRPCInstance.
+type GRPC struct {
+	files  *protoregistry.Files
+	server *grpc.Server
+	conn   *grpc.ClientConn
+}
+
+var _ spec.Runner = (*GRPC)(nil)
+
+// NewGRPCRunner serves server on an in-memory bufconn listener and returns a
+// runner that drives it by marshaling Lua tables to and from protobuf
+// messages found in files.
+func NewGRPCRunner(server *grpc.Server, files *protoregistry.Files) *GRPC {
+	listener := bufconn.Listen(grpcBufSize)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(_ context.Context, _ string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		panic(fmt.Errorf("grpc.Run: unable to dial bufconn: %w", err))
+	}
+
+	return &GRPC{
+		files:  files,
+		server: server,
+		conn:   conn,
+	}
+}
+
+// Close shuts down the client connection and the bufconn server, mirroring
+// WS.Close/SSE.Close: call it once per factory lifetime, not per instance.
+func (g *GRPC) Close() {
+	_ = g.conn.Close()
+	g.server.GracefulStop()
+}
+
+func (g *GRPC) Name() string {
+	return "grpc"
+}
+
+func (g *GRPC) Functions() []*spec.Function {
+	return g.NewInstance(context.Background()).(*GRPCInstance).Functions()
+}
+
+// NewInstance returns a fresh gRPC runner backed by this factory's bufconn
+// connection, with its own metadata, status, and last response, so the spec
+// engine can run independent .lua files concurrently against the same
+// server.
+func (g *GRPC) NewInstance(_ context.Context) spec.Runner {
+	return &GRPCInstance{factory: g}
+}
+
+// GRPCInstance is the per-test gRPC runner handed out by GRPC.NewInstance.
+type GRPCInstance struct {
+	factory  *GRPC
+	metadata metadata.MD
+	status   *status.Status
+	response map[string]any
+}
+
+var _ spec.Runner = (*GRPCInstance)(nil)
+
+func (g *GRPCInstance) Name() string {
+	return g.factory.Name()
+}
+
+func (g *GRPCInstance) Functions() []*spec.Function {
+	return []*spec.Function{
+		{
+			Name: "addMetadata",
+			Args: []spec.Argument{
+				{
+					Name: "key",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The metadata key",
+				},
+				{
+					Name: "value",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The metadata value",
+				},
+			},
+			Doc:  "Add metadata to the request",
+			Func: g.addMetadata,
+		},
+		{
+			Name: "call",
+			Args: []spec.Argument{
+				{
+					Name: "service",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The fully qualified service name",
+				},
+				{
+					Name: "method",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The method name",
+				},
+				{
+					Name: "req?",
+					Type: []spec.ArgumentType{spec.ArgumentTypeTable},
+					Doc:  "The request message",
+				},
+			},
+			Doc:  "Call a gRPC method",
+			Func: g.call,
+		},
+		{
+			Name: "check",
+			Args: []spec.Argument{
+				{
+					Name: "status_code",
+					Type: []spec.ArgumentType{spec.ArgumentTypeNumber},
+					Doc:  "Expected gRPC status code",
+				},
+				{
+					Name: "resp",
+					Type: []spec.ArgumentType{spec.ArgumentTypeTable},
+					Doc:  "Expected response",
+				},
+			},
+			Doc:  "Check the response done by call",
+			Func: g.check,
+		},
+	}
+}
+
+func (g *GRPCInstance) addMetadata(L *lua.LState) int {
+	key := L.CheckString(1)
+	value := L.CheckString(2)
+
+	if g.metadata == nil {
+		g.metadata = metadata.MD{}
+	}
+	g.metadata.Append(key, value)
+
+	return 0
+}
+
+func (g *GRPCInstance) call(L *lua.LState) int {
+	ctx := L.Context()
+	service := L.CheckString(1)
+	method := L.CheckString(2)
+
+	methodDesc, err := g.findMethod(service, method)
+	if err != nil {
+		L.RaiseError("%v", err)
+		return 0
+	}
+
+	reqMsg := dynamicpb.NewMessage(methodDesc.Input())
+	if L.GetTop() > 2 {
+		tbl := L.CheckTable(3)
+		b, err := json.Marshal(luaTableToMap(tbl))
+		if err != nil {
+			L.RaiseError("unable to marshal request table: %v", err)
+			return 0
+		}
+		if err := protojson.Unmarshal(b, reqMsg); err != nil {
+			L.RaiseError("unable to unmarshal request into %s: %v", methodDesc.Input().FullName(), err)
+			return 0
+		}
+	}
+
+	reqJSON, _ := protojson.Marshal(reqMsg)
+	Info(ctx, reporter.Info{
+		Type:     reporter.InfoTypeRequest,
+		Title:    fmt.Sprintf("gRPC %s/%s", service, method),
+		Content:  string(reqJSON),
+		Language: "json",
+	})
+
+	outCtx := ctx
+	if g.metadata != nil {
+		outCtx = metadata.NewOutgoingContext(ctx, g.metadata)
+	}
+
+	respMsg := dynamicpb.NewMessage(methodDesc.Output())
+	fullMethod := fmt.Sprintf("/%s/%s", service, method)
+	invokeErr := g.factory.conn.Invoke(outCtx, fullMethod, reqMsg, respMsg)
+	g.status = status.Convert(invokeErr)
+
+	respJSON, _ := protojson.Marshal(respMsg)
+	g.response = map[string]any{}
+	_ = json.Unmarshal(respJSON, &g.response)
+
+	Info(ctx, reporter.Info{
+		Type:     reporter.InfoTypeResponse,
+		Title:    fmt.Sprintf("gRPC Response (%s)", g.status.Code()),
+		Content:  string(respJSON),
+		Language: "json",
+	})
+
+	if detailsJSON := statusDetailsJSON(g.status); detailsJSON != nil {
+		Info(ctx, reporter.Info{
+			Type:     reporter.InfoTypeResponse,
+			Title:    fmt.Sprintf("gRPC Status Details (%s)", g.status.Code()),
+			Content:  string(detailsJSON),
+			Language: "json",
+		})
+	}
+
+	return 0
+}
+
+func (g *GRPCInstance) check(L *lua.LState) int {
+	code := L.CheckInt(1)
+	tbl := L.CheckTable(2)
+
+	if g.status == nil {
+		L.RaiseError("call not called")
+		return 0
+	}
+
+	if int(g.status.Code()) != code {
+		L.RaiseError("expected status code %d, got %d: %s (details: %s)", code, g.status.Code(), g.status.Message(), statusDetailsJSON(g.status))
+		return 0
+	}
+
+	StdCheck(L, tbl, g.response)
+	return 0
+}
+
+// statusDetailsJSON renders a gRPC status's error details (e.g. a
+// BadRequest or RetryInfo proto) as a JSON array, so they show up in the
+// report instead of being silently dropped. Returns nil if there are none.
+func statusDetailsJSON(st *status.Status) []byte {
+	details := st.Details()
+	if len(details) == 0 {
+		return nil
+	}
+
+	out := make([]any, 0, len(details))
+	for _, d := range details {
+		msg, ok := d.(proto.Message)
+		if !ok {
+			out = append(out, fmt.Sprintf("%v", d))
+			continue
+		}
+
+		b, err := protojson.Marshal(msg)
+		if err != nil {
+			out = append(out, fmt.Sprintf("%v", d))
+			continue
+		}
+
+		var v any
+		if err := json.Unmarshal(b, &v); err != nil {
+			out = append(out, fmt.Sprintf("%v", d))
+			continue
+		}
+		out = append(out, v)
+	}
+
+	b, _ := json.Marshal(out)
+	return b
+}
+
+func (g *GRPCInstance) findMethod(service, method string) (protoreflect.MethodDescriptor, error) {
+	desc, err := g.factory.files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %q not found: %w", service, err)
+	}
+
+	svc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a service", service)
+	}
+
+	m := svc.Methods().ByName(protoreflect.Name(method))
+	if m == nil {
+		return nil, fmt.Errorf("method %q not found on service %q", method, service)
+	}
+
+	return m, nil
+}
+
+// luaTableToMap converts a Lua table built from a request body into the
+// map[string]any representation json.Marshal understands. *lua.LTable only
+// exposes its Metatable field to the standard library's reflection-based
+// encoding/json, so marshaling it directly produces {"Metatable":null}
+// instead of the table's actual contents; the table must be walked and
+// converted by hand first.
+func luaTableToMap(tbl *lua.LTable) map[string]any {
+	m := make(map[string]any, tbl.Len())
+	tbl.ForEach(func(k, v lua.LValue) {
+		m[k.String()] = luaValueToAny(v)
+	})
+	return m
+}
+
+// luaValueToAny converts a single Lua value into the plain Go representation
+// (map[string]any, []any, string, float64, bool, or nil) used by
+// luaTableToMap, recursing into nested tables. A table is treated as an
+// array when it has a non-empty array part, and as an object otherwise.
+func luaValueToAny(v lua.LValue) any {
+	switch t := v.(type) {
+	case *lua.LTable:
+		if n := t.Len(); n > 0 {
+			arr := make([]any, 0, n)
+			t.ForEach(func(_, item lua.LValue) {
+				arr = append(arr, luaValueToAny(item))
+			})
+			return arr
+		}
+		return luaTableToMap(t)
+	case lua.LString:
+		return string(t)
+	case lua.LNumber:
+		return float64(t)
+	case lua.LBool:
+		return bool(t)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// newEchoServiceFiles builds a protoregistry.Files for a single
+// "test.EchoService/Say" method (EchoRequest{message} -> EchoResponse{message})
+// from a hand-built FileDescriptorProto, so the test doesn't depend on
+// protoc/codegen being available.
+func newEchoServiceFiles(t *testing.T) *protoregistry.Files {
+	t.Helper()
+
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("echo.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("EchoRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("message"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						JsonName: proto.String("message"),
+					},
+				},
+			},
+			{
+				Name: proto.String("EchoResponse"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("message"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						JsonName: proto.String("message"),
+					},
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("EchoService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("Say"),
+						InputType:  proto.String(".test.EchoRequest"),
+						OutputType: proto.String(".test.EchoResponse"),
+					},
+				},
+			},
+		},
+	}
+
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fdProto}})
+	if err != nil {
+		t.Fatalf("NewFiles: %v", err)
+	}
+	return files
+}
+
+// registerEchoService registers a handwritten "test.EchoService/Say" handler
+// on server that echoes the request's "message" field back, using dynamicpb
+// so it needs no generated stubs.
+func registerEchoService(server *grpc.Server, files *protoregistry.Files) {
+	desc, err := files.FindDescriptorByName("test.EchoService")
+	if err != nil {
+		panic(err)
+	}
+	svc := desc.(protoreflect.ServiceDescriptor)
+	method := svc.Methods().ByName("Say")
+	reqDesc := method.Input()
+	respDesc := method.Output()
+
+	handler := func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := dynamicpb.NewMessage(reqDesc)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+
+		say := func(ctx context.Context, req any) (any, error) {
+			in := req.(*dynamicpb.Message)
+			out := dynamicpb.NewMessage(respDesc)
+			out.Set(respDesc.Fields().ByName("message"), in.Get(reqDesc.Fields().ByName("message")))
+			return out, nil
+		}
+
+		if interceptor == nil {
+			return say(ctx, in)
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: "/test.EchoService/Say"}, say)
+	}
+
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.EchoService",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "Say", Handler: handler},
+		},
+		Metadata: "echo.proto",
+	}, nil)
+}
+
+func TestGRPCCallAndCheck(t *testing.T) {
+	files := newEchoServiceFiles(t)
+	server := grpc.NewServer()
+	registerEchoService(server, files)
+
+	factory := NewGRPCRunner(server, files)
+	defer factory.Close()
+
+	instance := factory.NewInstance(context.Background()).(*GRPCInstance)
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	callFn := L.NewFunction(instance.call)
+	reqTbl := L.NewTable()
+	reqTbl.RawSetString("message", lua.LString("hello"))
+	if err := L.CallByParam(lua.P{Fn: callFn, NRet: 0, Protect: true},
+		lua.LString("test.EchoService"), lua.LString("Say"), reqTbl); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+
+	if instance.response["message"] != "hello" {
+		t.Fatalf("expected response message %q, got %v", "hello", instance.response["message"])
+	}
+
+	checkTbl := L.NewTable()
+	checkTbl.RawSetString("message", lua.LString("hello"))
+	checkFn := L.NewFunction(instance.check)
+	if err := L.CallByParam(lua.P{Fn: checkFn, NRet: 0, Protect: true}, lua.LNumber(0), checkTbl); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+}
+
+func TestGRPCCheckFailsOnStatusCodeMismatch(t *testing.T) {
+	files := newEchoServiceFiles(t)
+	server := grpc.NewServer()
+	registerEchoService(server, files)
+
+	factory := NewGRPCRunner(server, files)
+	defer factory.Close()
+
+	instance := factory.NewInstance(context.Background()).(*GRPCInstance)
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	callFn := L.NewFunction(instance.call)
+	reqTbl := L.NewTable()
+	reqTbl.RawSetString("message", lua.LString("hi"))
+	if err := L.CallByParam(lua.P{Fn: callFn, NRet: 0, Protect: true},
+		lua.LString("test.EchoService"), lua.LString("Say"), reqTbl); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+
+	checkTbl := L.NewTable()
+	checkFn := L.NewFunction(instance.check)
+	err := L.CallByParam(lua.P{Fn: checkFn, NRet: 0, Protect: true}, lua.LNumber(5), checkTbl)
+	if err == nil {
+		t.Fatal("expected check to fail on status code mismatch")
+	}
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nais/tester/lua/spec"
+)
+
+// Factory is implemented by the *REST/*PubSub/*GRPC/*WS/*SSE factories
+// returned by the NewXRunner constructors: it hands out a fresh, isolated
+// spec.Runner per test so RunSpecs can run many specs against the same
+// backend concurrently without them racing on each other's headers,
+// metadata, or received messages.
+type Factory interface {
+	NewInstance(ctx context.Context) spec.Runner
+}
+
+// RunOptions configures RunSpecs.
+type RunOptions struct {
+	// Parallel is the number of specs to run concurrently. Values <= 1 run
+	// specs one at a time, in order.
+	Parallel int
+}
+
+// Spec is one spec to run: Name identifies it for the caller's error
+// reporting, Run is whatever loads and executes it against the fresh runner
+// instance it's handed. Kept as a func so this package doesn't need to know
+// how .lua files are parsed and dispatched.
+type Spec struct {
+	Name string
+	Run  func(ctx context.Context, instance spec.Runner) error
+}
+
+// RunSpecs runs each spec against its own instance obtained from
+// factory.NewInstance, so the per-test state introduced by the
+// REST/PubSub/GRPC/WS/SSE factory split (see e.g. REST.NewInstance) never
+// leaks across specs running at the same time. Up to opts.Parallel specs run
+// at once; errs[i] holds the error (if any) for specs[i], in input order.
+//
+// This is the runner-side dispatch primitive only. The spec engine that
+// discovers .lua files and drives each one's Lua state isn't part of this
+// tree, so nothing in this package calls RunSpecs yet, and there is no
+// -parallel CLI flag: wiring a real .lua file's Spec.Run and a flag that
+// sets RunOptions.Parallel is a follow-up in the spec engine, not something
+// this package can deliver on its own.
+func RunSpecs(ctx context.Context, factory Factory, specs []Spec, opts RunOptions) []error {
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	errs := make([]error, len(specs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, s := range specs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, s Spec) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = s.Run(ctx, factory.NewInstance(ctx))
+		}(i, s)
+	}
+
+	wg.Wait()
+	return errs
+}
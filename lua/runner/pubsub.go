@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"sync"
@@ -21,18 +22,38 @@ type PubSubMessage struct {
 
 type PubSubHook func(topic string, msg PubSubMessage) error
 
+// PubSub is the PubSub factory counterpart to REST — see REST for the
+// factory/instance split rationale; topics live on the PubSubInstance.
 type PubSub struct {
-	lock      sync.Mutex
-	topics    map[string]PubSubTopic
-	doPublish PubSubHook
+	doPublish   PubSubHook
+	snapshotDir string
 }
 
 var _ spec.Runner = (*PubSub)(nil)
 
-func NewPubSub(doPublish PubSubHook) *PubSub {
-	return &PubSub{
-		doPublish: doPublish,
+// PubSubOption configures a PubSub runner constructed with NewPubSub.
+type PubSubOption func(*PubSub)
+
+// WithPubSubSnapshotDir overrides the directory checkSnapshot reads and
+// writes golden files under, which otherwise defaults to
+// "testdata/snapshots".
+func WithPubSubSnapshotDir(dir string) PubSubOption {
+	return func(p *PubSub) {
+		p.snapshotDir = dir
+	}
+}
+
+func NewPubSub(doPublish PubSubHook, opts ...PubSubOption) *PubSub {
+	p := &PubSub{
+		doPublish:   doPublish,
+		snapshotDir: defaultSnapshotDir,
+	}
+
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	return p
 }
 
 func (p *PubSub) Name() string {
@@ -40,6 +61,37 @@ func (p *PubSub) Name() string {
 }
 
 func (p *PubSub) Functions() []*spec.Function {
+	return p.NewInstance(context.Background()).(*PubSubInstance).Functions()
+}
+
+func (p *PubSub) HelperFunctions() []*spec.Function {
+	return p.NewInstance(context.Background()).(*PubSubInstance).HelperFunctions()
+}
+
+// NewInstance returns a fresh PubSub runner backed by this factory, with its
+// own topics, so the spec engine can run independent .lua files concurrently.
+// Test setup code that wires a fake pubsub client to Send/Receive should use
+// the concrete *PubSubInstance returned here rather than the factory, so
+// messages land in the right test's topics.
+func (p *PubSub) NewInstance(_ context.Context) spec.Runner {
+	return &PubSubInstance{factory: p}
+}
+
+// PubSubInstance is the per-test PubSub runner handed out by
+// PubSub.NewInstance.
+type PubSubInstance struct {
+	factory *PubSub
+	lock    sync.Mutex
+	topics  map[string]PubSubTopic
+}
+
+var _ spec.Runner = (*PubSubInstance)(nil)
+
+func (p *PubSubInstance) Name() string {
+	return p.factory.Name()
+}
+
+func (p *PubSubInstance) Functions() []*spec.Function {
 	return []*spec.Function{
 		{
 			Name: "check",
@@ -58,10 +110,100 @@ func (p *PubSub) Functions() []*spec.Function {
 			Doc:  "Check comment",
 			Func: p.check,
 		},
+		{
+			Name: "checkSnapshot",
+			Args: []spec.Argument{
+				{
+					Name: "topic",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The topic to check",
+				},
+				{
+					Name: "snapshot",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "Path to the golden file, relative to the snapshot directory",
+				},
+			},
+			Doc:  "Check the first received message on the topic against a golden file",
+			Func: p.checkSnapshot,
+		},
+		{
+			Name: "checkInOrder",
+			Args: []spec.Argument{
+				{
+					Name: "topic",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The topic to check",
+				},
+				{
+					Name: "messages",
+					Type: []spec.ArgumentType{spec.ArgumentTypeTable},
+					Doc:  "The expected sequence of messages, matched contiguously against Received",
+				},
+			},
+			Doc:  "Check that a sequence of messages was received in order",
+			Func: p.checkInOrder,
+		},
+		{
+			Name: "checkNone",
+			Args: []spec.Argument{
+				{
+					Name: "topic",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The topic to check",
+				},
+				{
+					Name: "resp",
+					Type: []spec.ArgumentType{spec.ArgumentTypeTable},
+					Doc:  "The message that must not have been received",
+				},
+			},
+			Doc:  "Check that no received message on the topic matches",
+			Func: p.checkNone,
+		},
+		{
+			Name: "checkWithAttributes",
+			Args: []spec.Argument{
+				{
+					Name: "topic",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The topic to check",
+				},
+				{
+					Name: "filter",
+					Type: []spec.ArgumentType{spec.ArgumentTypeTable},
+					Doc:  "A table with an 'attributes' field used to filter candidate messages",
+				},
+				{
+					Name: "resp",
+					Type: []spec.ArgumentType{spec.ArgumentTypeTable},
+					Doc:  "The message body to check for, among candidates matching the attribute filter",
+				},
+			},
+			Doc:  "Check a message matching the given attributes",
+			Func: p.checkWithAttributes,
+		},
+		{
+			Name: "consume",
+			Args: []spec.Argument{
+				{
+					Name: "topic",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The topic to consume from",
+				},
+				{
+					Name: "n",
+					Type: []spec.ArgumentType{spec.ArgumentTypeNumber},
+					Doc:  "The number of received messages to pop, oldest first",
+				},
+			},
+			Doc:  "Pop messages off the topic so later check calls don't re-match them",
+			Func: p.consume,
+		},
 	}
 }
 
-func (p *PubSub) HelperFunctions() []*spec.Function {
+func (p *PubSubInstance) HelperFunctions() []*spec.Function {
 	return []*spec.Function{
 		{
 			Name: "emptyPubSubTopic",
@@ -78,7 +220,7 @@ func (p *PubSub) HelperFunctions() []*spec.Function {
 	}
 }
 
-func (p *PubSub) check(L *lua.LState) int {
+func (p *PubSubInstance) check(L *lua.LState) int {
 	topic := L.CheckString(1)
 	tbl := L.CheckTable(2)
 
@@ -93,15 +235,7 @@ func (p *PubSub) check(L *lua.LState) int {
 
 	var errs []string
 	for _, msg := range msgs {
-		target := map[string]any{}
-		b := map[string]any{
-			"data":       msg.Msg,
-			"attributes": msg.Attributes,
-		}
-		bs, _ := json.Marshal(b)
-		_ = json.Unmarshal(bs, &target)
-
-		if err := StdCheckError(L.Context(), tbl, target); err != nil {
+		if err := StdCheckError(L.Context(), tbl, messageTarget(msg)); err != nil {
 			errs = append(errs, err.Error())
 		} else {
 			return 0
@@ -116,7 +250,189 @@ func (p *PubSub) check(L *lua.LState) int {
 	return 0
 }
 
-func (p *PubSub) emptyTopic(L *lua.LState) int {
+func (p *PubSubInstance) checkSnapshot(L *lua.LState) int {
+	topic := L.CheckString(1)
+	name := L.CheckString(2)
+
+	if !p.hasTopic(topic) {
+		L.RaiseError("topic %q not registered, has: %v", topic, p.topicsNames())
+		return 0
+	}
+
+	msgs := p.messages(topic)
+	if len(msgs) == 0 {
+		L.RaiseError("no messages received on topic %q", topic)
+		return 0
+	}
+
+	if err := checkSnapshot(L, p.factory.snapshotDir, name, messageTarget(msgs[0])); err != nil {
+		L.RaiseError("%v", err)
+	}
+
+	return 0
+}
+
+func (p *PubSubInstance) checkInOrder(L *lua.LState) int {
+	topic := L.CheckString(1)
+	seq := L.CheckTable(2)
+
+	if !p.hasTopic(topic) {
+		L.RaiseError("topic %q not registered, has: %v", topic, p.topicsNames())
+		return 0
+	}
+
+	var want []*lua.LTable
+	seq.ForEach(func(_, v lua.LValue) {
+		if tbl, ok := v.(*lua.LTable); ok {
+			want = append(want, tbl)
+		}
+	})
+
+	msgs := p.messages(topic)
+	for start := 0; start+len(want) <= len(msgs); start++ {
+		if inOrderMatch(L, want, msgs[start:start+len(want)]) {
+			return 0
+		}
+	}
+
+	L.RaiseError("messages on topic %q did not contain the expected sequence of %d messages", topic, len(want))
+	return 0
+}
+
+func (p *PubSubInstance) checkNone(L *lua.LState) int {
+	topic := L.CheckString(1)
+	tbl := L.CheckTable(2)
+
+	if !p.hasTopic(topic) {
+		return 0
+	}
+
+	for _, msg := range p.messages(topic) {
+		if StdCheckError(L.Context(), tbl, bodyTarget(msg)) == nil {
+			L.RaiseError("expected no messages on topic %q matching, but found one", topic)
+			return 0
+		}
+	}
+
+	return 0
+}
+
+func (p *PubSubInstance) checkWithAttributes(L *lua.LState) int {
+	topic := L.CheckString(1)
+	filterTbl := L.CheckTable(2)
+	bodyTbl := L.CheckTable(3)
+
+	if !p.hasTopic(topic) {
+		L.RaiseError("topic %q not registered, has: %v", topic, p.topicsNames())
+		return 0
+	}
+
+	attrFilter, ok := filterTbl.RawGetString("attributes").(*lua.LTable)
+	if !ok {
+		L.RaiseError("checkWithAttributes: expected a filter table with an 'attributes' field")
+		return 0
+	}
+
+	var errs []string
+	for _, msg := range p.messages(topic) {
+		if !attributesMatch(attrFilter, msg.Attributes) {
+			continue
+		}
+		if err := StdCheckError(L.Context(), bodyTbl, bodyTarget(msg)); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		return 0
+	}
+
+	if len(errs) > 0 {
+		L.RaiseError("%v", strings.Join(errs, "\n"))
+		return 0
+	}
+
+	L.RaiseError("no matching messages received on topic %q with the given attributes", topic)
+	return 0
+}
+
+func (p *PubSubInstance) consume(L *lua.LState) int {
+	topic := L.CheckString(1)
+	n := L.CheckInt(2)
+
+	if n < 0 {
+		L.RaiseError("consume: n must not be negative, got %d", n)
+		return 0
+	}
+
+	if !p.hasTopic(topic) {
+		L.RaiseError("topic %q not registered, has: %v", topic, p.topicsNames())
+		return 0
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.topics[topic] = consumeReceived(p.topics[topic], n)
+
+	return 0
+}
+
+// consumeReceived pops up to n messages off the front of t.Received, oldest
+// first, clamping n to the number of messages actually available. n must
+// not be negative.
+func consumeReceived(t PubSubTopic, n int) PubSubTopic {
+	if n > len(t.Received) {
+		n = len(t.Received)
+	}
+	t.Received = t.Received[n:]
+	return t
+}
+
+// messageTarget flattens a PubSubMessage into the data/attributes shape that
+// check/checkSnapshot's matcher tables are checked against.
+func messageTarget(msg PubSubMessage) map[string]any {
+	target := map[string]any{}
+	b := map[string]any{
+		"data":       msg.Msg,
+		"attributes": msg.Attributes,
+	}
+	bs, _ := json.Marshal(b)
+	_ = json.Unmarshal(bs, &target)
+	return target
+}
+
+// bodyTarget flattens a PubSubMessage's body into the plain map[string]any
+// shape that checkInOrder/checkNone/checkWithAttributes's body_table
+// matchers are checked against — unlike messageTarget, it's not wrapped in a
+// data/attributes envelope, since those functions match the message body
+// directly (attribute filtering, where needed, is done separately via
+// attributesMatch).
+func bodyTarget(msg PubSubMessage) map[string]any {
+	target := map[string]any{}
+	b, _ := json.Marshal(msg.Msg)
+	_ = json.Unmarshal(b, &target)
+	return target
+}
+
+func inOrderMatch(L *lua.LState, want []*lua.LTable, got []PubSubMessage) bool {
+	for i, tbl := range want {
+		if err := StdCheckError(L.Context(), tbl, bodyTarget(got[i])); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func attributesMatch(filter *lua.LTable, attrs map[string]string) bool {
+	match := true
+	filter.ForEach(func(k, v lua.LValue) {
+		if attrs[k.String()] != v.String() {
+			match = false
+		}
+	})
+	return match
+}
+
+func (p *PubSubInstance) emptyTopic(L *lua.LState) int {
 	topic := L.CheckString(1)
 
 	p.lock.Lock()
@@ -130,7 +446,7 @@ func (p *PubSub) emptyTopic(L *lua.LState) int {
 	return 0
 }
 
-func (p *PubSub) Send(topic string, msg PubSubMessage) {
+func (p *PubSubInstance) Send(topic string, msg PubSubMessage) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -147,7 +463,7 @@ func (p *PubSub) Send(topic string, msg PubSubMessage) {
 	p.topics[topic] = t
 }
 
-func (p *PubSub) Receive(topic string, msg PubSubMessage) {
+func (p *PubSubInstance) Receive(topic string, msg PubSubMessage) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -163,7 +479,7 @@ func (p *PubSub) Receive(topic string, msg PubSubMessage) {
 	p.topics[topic] = t
 }
 
-func (p *PubSub) hasTopic(name string) bool {
+func (p *PubSubInstance) hasTopic(name string) bool {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -171,7 +487,7 @@ func (p *PubSub) hasTopic(name string) bool {
 	return ok
 }
 
-func (p *PubSub) topicsNames() []string {
+func (p *PubSubInstance) topicsNames() []string {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -183,7 +499,7 @@ func (p *PubSub) topicsNames() []string {
 	return names
 }
 
-func (p *PubSub) messages(topic string) []PubSubMessage {
+func (p *PubSubInstance) messages(topic string) []PubSubMessage {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -0,0 +1,243 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestConsumeRejectsNegativeN(t *testing.T) {
+	factory := NewPubSub(nil)
+	instance := factory.NewInstance(context.Background()).(*PubSubInstance)
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}})
+
+	L := lua.NewState()
+	defer L.Close()
+
+	fn := L.NewFunction(instance.consume)
+	err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString("topic"), lua.LNumber(-1))
+	if err == nil {
+		t.Fatal("expected consume(topic, -1) to raise an error, got nil")
+	}
+
+	if got := instance.messages("topic"); len(got) != 1 {
+		t.Fatalf("expected the rejected call to leave messages untouched, got %d", len(got))
+	}
+}
+
+func TestConsumePopsOldestFirst(t *testing.T) {
+	factory := NewPubSub(nil)
+	instance := factory.NewInstance(context.Background()).(*PubSubInstance)
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}})
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(2)}})
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(3)}})
+
+	L := lua.NewState()
+	defer L.Close()
+
+	fn := L.NewFunction(instance.consume)
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString("topic"), lua.LNumber(2)); err != nil {
+		t.Fatalf("consume(topic, 2): %v", err)
+	}
+
+	got := instance.messages("topic")
+	if len(got) != 1 || got[0].Msg["n"] != float64(3) {
+		t.Fatalf("expected only the third message to remain, got %v", got)
+	}
+}
+
+func TestConsumeClampsNToAvailable(t *testing.T) {
+	factory := NewPubSub(nil)
+	instance := factory.NewInstance(context.Background()).(*PubSubInstance)
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}})
+
+	L := lua.NewState()
+	defer L.Close()
+
+	fn := L.NewFunction(instance.consume)
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString("topic"), lua.LNumber(5)); err != nil {
+		t.Fatalf("consume(topic, 5): %v", err)
+	}
+
+	if got := instance.messages("topic"); len(got) != 0 {
+		t.Fatalf("expected consuming more than available to empty the topic, got %v", got)
+	}
+}
+
+func TestAttributesMatch(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	filter := L.NewTable()
+	filter.RawSetString("region", lua.LString("eu-west-1"))
+
+	cases := []struct {
+		name  string
+		attrs map[string]string
+		want  bool
+	}{
+		{"matches", map[string]string{"region": "eu-west-1", "other": "x"}, true},
+		{"mismatch", map[string]string{"region": "us-east-1"}, false},
+		{"missing", map[string]string{"other": "x"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := attributesMatch(filter, c.attrs); got != c.want {
+				t.Errorf("attributesMatch(%v) = %v, want %v", c.attrs, got, c.want)
+			}
+		})
+	}
+}
+
+// bodyTable builds the body_table shape checked against bodyTarget by
+// checkInOrder/checkNone/checkWithAttributes (a plain table of fields, not
+// wrapped in a data/attributes envelope like check's matcher table).
+func bodyTable(L *lua.LState, data map[string]any) *lua.LTable {
+	tbl := L.NewTable()
+	for k, v := range data {
+		switch val := v.(type) {
+		case string:
+			tbl.RawSetString(k, lua.LString(val))
+		case float64:
+			tbl.RawSetString(k, lua.LNumber(val))
+		}
+	}
+	return tbl
+}
+
+func TestCheckInOrderMatchesContiguousSequence(t *testing.T) {
+	factory := NewPubSub(nil)
+	instance := factory.NewInstance(context.Background()).(*PubSubInstance)
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}})
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(2)}})
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(3)}})
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	seq := L.NewTable()
+	seq.Append(bodyTable(L, map[string]any{"n": float64(2)}))
+	seq.Append(bodyTable(L, map[string]any{"n": float64(3)}))
+
+	fn := L.NewFunction(instance.checkInOrder)
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString("topic"), seq); err != nil {
+		t.Fatalf("checkInOrder: %v", err)
+	}
+}
+
+func TestCheckInOrderFailsWhenNotContiguous(t *testing.T) {
+	factory := NewPubSub(nil)
+	instance := factory.NewInstance(context.Background()).(*PubSubInstance)
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}})
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(3)}})
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(2)}})
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	seq := L.NewTable()
+	seq.Append(bodyTable(L, map[string]any{"n": float64(1)}))
+	seq.Append(bodyTable(L, map[string]any{"n": float64(2)}))
+
+	fn := L.NewFunction(instance.checkInOrder)
+	err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString("topic"), seq)
+	if err == nil {
+		t.Fatal("expected checkInOrder to fail when messages aren't received contiguously")
+	}
+}
+
+func TestCheckNoneFailsWhenAMessageMatches(t *testing.T) {
+	factory := NewPubSub(nil)
+	instance := factory.NewInstance(context.Background()).(*PubSubInstance)
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}})
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	fn := L.NewFunction(instance.checkNone)
+	err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString("topic"), bodyTable(L, map[string]any{"n": float64(1)}))
+	if err == nil {
+		t.Fatal("expected checkNone to fail when a received message matches")
+	}
+}
+
+func TestCheckNoneOKWhenNoMessageMatches(t *testing.T) {
+	factory := NewPubSub(nil)
+	instance := factory.NewInstance(context.Background()).(*PubSubInstance)
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}})
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	fn := L.NewFunction(instance.checkNone)
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString("topic"), bodyTable(L, map[string]any{"n": float64(2)})); err != nil {
+		t.Fatalf("checkNone: %v", err)
+	}
+}
+
+func TestCheckWithAttributesNarrowsCandidates(t *testing.T) {
+	factory := NewPubSub(nil)
+	instance := factory.NewInstance(context.Background()).(*PubSubInstance)
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}, Attributes: map[string]string{"region": "us-east-1"}})
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}, Attributes: map[string]string{"region": "eu-west-1"}})
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	attrs := L.NewTable()
+	attrs.RawSetString("region", lua.LString("eu-west-1"))
+	filter := L.NewTable()
+	filter.RawSetString("attributes", attrs)
+
+	fn := L.NewFunction(instance.checkWithAttributes)
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString("topic"), filter, bodyTable(L, map[string]any{"n": float64(1)})); err != nil {
+		t.Fatalf("checkWithAttributes: %v", err)
+	}
+}
+
+func TestCheckWithAttributesFailsWhenNoCandidateMatchesFilter(t *testing.T) {
+	factory := NewPubSub(nil)
+	instance := factory.NewInstance(context.Background()).(*PubSubInstance)
+	instance.Receive("topic", PubSubMessage{Msg: map[string]any{"n": float64(1)}, Attributes: map[string]string{"region": "us-east-1"}})
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	attrs := L.NewTable()
+	attrs.RawSetString("region", lua.LString("eu-west-1"))
+	filter := L.NewTable()
+	filter.RawSetString("attributes", attrs)
+
+	fn := L.NewFunction(instance.checkWithAttributes)
+	err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString("topic"), filter, bodyTable(L, map[string]any{"n": float64(1)}))
+	if err == nil {
+		t.Fatal("expected checkWithAttributes to fail when no candidate matches the attribute filter")
+	}
+}
+
+func TestMessageTarget(t *testing.T) {
+	msg := PubSubMessage{
+		Msg:        map[string]any{"hello": "world"},
+		Attributes: map[string]string{"region": "eu-west-1"},
+	}
+
+	target := messageTarget(msg)
+
+	data, ok := target["data"].(map[string]any)
+	if !ok || data["hello"] != "world" {
+		t.Fatalf("expected data.hello == world, got %v", target)
+	}
+
+	attrs, ok := target["attributes"].(map[string]any)
+	if !ok || attrs["region"] != "eu-west-1" {
+		t.Fatalf("expected attributes.region == eu-west-1, got %v", target)
+	}
+}
@@ -2,7 +2,7 @@ package runner
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,16 +14,50 @@ import (
 	lua "github.com/yuin/gopher-lua"
 )
 
+// REST is the parallel-safe factory for REST test instances: shared config
+// (handler, codecs, snapshot dir) lives here, per-test state (headers, last
+// response) lives on the RestInstance NewInstance returns, so concurrent
+// specs don't race on each other's state.
 type REST struct {
-	server   http.Handler
-	headers  http.Header
-	response *httptest.ResponseRecorder
+	server      http.Handler
+	codecs      map[string]Codec
+	snapshotDir string
 }
 
 var _ spec.Runner = (*REST)(nil)
 
-func NewRestRunner(server http.Handler) *REST {
-	return &REST{server: server}
+// RestOption configures a REST runner constructed with NewRestRunner.
+type RestOption func(*REST)
+
+// WithCodec registers a Codec to marshal and unmarshal bodies for the given
+// content type, overriding the built-in codec if one is already registered
+// for it.
+func WithCodec(contentType string, codec Codec) RestOption {
+	return func(r *REST) {
+		r.codecs[contentType] = codec
+	}
+}
+
+// WithSnapshotDir overrides the directory checkSnapshot reads and writes
+// golden files under, which otherwise defaults to "testdata/snapshots".
+func WithSnapshotDir(dir string) RestOption {
+	return func(r *REST) {
+		r.snapshotDir = dir
+	}
+}
+
+func NewRestRunner(server http.Handler, opts ...RestOption) *REST {
+	r := &REST{
+		server:      server,
+		codecs:      defaultCodecs(),
+		snapshotDir: defaultSnapshotDir,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
 func (r *REST) Name() string {
@@ -31,6 +65,30 @@ func (r *REST) Name() string {
 }
 
 func (r *REST) Functions() []*spec.Function {
+	return r.NewInstance(context.Background()).(*RestInstance).Functions()
+}
+
+// NewInstance returns a fresh REST runner backed by this factory, with its
+// own headers and last response, so RunSpecs (or any other caller) can run
+// independent .lua files concurrently against the same server.
+func (r *REST) NewInstance(_ context.Context) spec.Runner {
+	return &RestInstance{factory: r}
+}
+
+// RestInstance is the per-test REST runner handed out by REST.NewInstance.
+type RestInstance struct {
+	factory  *REST
+	headers  http.Header
+	response *httptest.ResponseRecorder
+}
+
+var _ spec.Runner = (*RestInstance)(nil)
+
+func (r *RestInstance) Name() string {
+	return r.factory.Name()
+}
+
+func (r *RestInstance) Functions() []*spec.Function {
 	return []*spec.Function{
 		{
 			Name: "addHeader",
@@ -88,10 +146,27 @@ func (r *REST) Functions() []*spec.Function {
 			Doc:  "Check the response done by send",
 			Func: r.check,
 		},
+		{
+			Name: "checkSnapshot",
+			Args: []spec.Argument{
+				{
+					Name: "status_code",
+					Type: []spec.ArgumentType{spec.ArgumentTypeNumber},
+					Doc:  "Expected status code",
+				},
+				{
+					Name: "snapshot",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "Path to the golden file, relative to the snapshot directory",
+				},
+			},
+			Doc:  "Check the response done by send against a golden file",
+			Func: r.checkSnapshot,
+		},
 	}
 }
 
-func (r *REST) send(L *lua.LState) int {
+func (r *RestInstance) send(L *lua.LState) int {
 	if r.response != nil {
 		r.response = nil
 	}
@@ -108,7 +183,7 @@ func (r *REST) send(L *lua.LState) int {
 			body = strings.NewReader(bodyContent)
 		case *lua.LTable:
 			tbl := L.CheckTable(3)
-			b, err := json.Marshal(tbl)
+			b, err := r.encoder().Marshal(luaTableToMap(tbl))
 			if err != nil {
 				L.RaiseError("unable to marshal table: %v", err)
 			}
@@ -139,7 +214,7 @@ func (r *REST) send(L *lua.LState) int {
 	}
 
 	r.response = httptest.NewRecorder()
-	r.server.ServeHTTP(r.response, req)
+	r.factory.server.ServeHTTP(r.response, req)
 
 	// Log the response
 	Info(ctx, reporter.Info{
@@ -152,7 +227,7 @@ func (r *REST) send(L *lua.LState) int {
 	return 0
 }
 
-func (r *REST) check(L *lua.LState) int {
+func (r *RestInstance) check(L *lua.LState) int {
 	code := L.CheckInt(1)
 	tbl := L.CheckTable(2)
 
@@ -166,8 +241,8 @@ func (r *REST) check(L *lua.LState) int {
 		return 0
 	}
 
-	var res map[string]interface{}
-	if err := json.Unmarshal(r.response.Body.Bytes(), &res); err != nil {
+	var res map[string]any
+	if err := r.codecFor(r.response.Header().Get("Content-Type")).Unmarshal(r.response.Body.Bytes(), &res); err != nil {
 		L.RaiseError("unable to unmarshal response: %v", err)
 		return 0
 	}
@@ -176,7 +251,61 @@ func (r *REST) check(L *lua.LState) int {
 	return 0
 }
 
-func (r *REST) addHeader(L *lua.LState) int {
+func (r *RestInstance) checkSnapshot(L *lua.LState) int {
+	code := L.CheckInt(1)
+	name := L.CheckString(2)
+
+	if r.response == nil {
+		L.RaiseError("send not called")
+		return 0
+	}
+
+	if r.response.Code != code {
+		L.RaiseError("expected response code %d, got %d\n%v", code, r.response.Code, r.response.Body.String())
+		return 0
+	}
+
+	var res map[string]any
+	if err := r.codecFor(r.response.Header().Get("Content-Type")).Unmarshal(r.response.Body.Bytes(), &res); err != nil {
+		L.RaiseError("unable to unmarshal response: %v", err)
+		return 0
+	}
+
+	if err := checkSnapshot(L, r.factory.snapshotDir, name, res); err != nil {
+		L.RaiseError("%v", err)
+	}
+
+	return 0
+}
+
+// encoder returns the codec to use for marshaling a request body, based on
+// the Content-Type header set via addHeader, defaulting to JSON.
+func (r *RestInstance) encoder() Codec {
+	contentType := ContentTypeJSON
+	if r.headers != nil {
+		if ct := r.headers.Get("Content-Type"); ct != "" {
+			contentType = ct
+		}
+	}
+
+	return r.codecFor(contentType)
+}
+
+// codecFor resolves the codec registered for contentType, trying an exact
+// match first (so codecs keyed by a parameterized content type, e.g. for a
+// specific protobuf message, can be registered) and falling back to the
+// base content type with any parameters stripped.
+func (r *RestInstance) codecFor(contentType string) Codec {
+	if codec, ok := r.factory.codecs[contentType]; ok {
+		return codec
+	}
+	if codec, ok := r.factory.codecs[contentTypeKey(contentType)]; ok {
+		return codec
+	}
+	return jsonCodec{}
+}
+
+func (r *RestInstance) addHeader(L *lua.LState) int {
 	key := L.CheckString(1)
 	value := L.CheckString(2)
 
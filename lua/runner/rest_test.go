@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestSendEncodesTableBody(t *testing.T) {
+	var gotBody map[string]any
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request body: %v", err)
+			return
+		}
+		if err := json.Unmarshal(b, &gotBody); err != nil {
+			t.Errorf("unmarshaling request body %q: %v", b, err)
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+
+	factory := NewRestRunner(handler)
+	instance := factory.NewInstance(context.Background()).(*RestInstance)
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	body := L.NewTable()
+	body.RawSetString("name", lua.LString("alice"))
+	body.RawSetString("age", lua.LNumber(30))
+
+	fn := L.NewFunction(instance.send)
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true},
+		lua.LString("POST"), lua.LString("/users"), body); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	want := map[string]any{"name": "alice", "age": float64(30)}
+	if gotBody["name"] != want["name"] || gotBody["age"] != want["age"] {
+		t.Fatalf("expected server to receive %v, got %v", want, gotBody)
+	}
+}
+
+func TestCheckMatchesJSONResponse(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	factory := NewRestRunner(handler)
+	instance := factory.NewInstance(context.Background()).(*RestInstance)
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	sendFn := L.NewFunction(instance.send)
+	if err := L.CallByParam(lua.P{Fn: sendFn, NRet: 0, Protect: true}, lua.LString("GET"), lua.LString("/health")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	want := L.NewTable()
+	want.RawSetString("status", lua.LString("ok"))
+
+	checkFn := L.NewFunction(instance.check)
+	if err := L.CallByParam(lua.P{Fn: checkFn, NRet: 0, Protect: true}, lua.LNumber(200), want); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/nais/tester/lua/reporter"
+	"github.com/pmezard/go-difflib/difflib"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// SaveFunc persists an updated expected value during a check. StdCheckError's
+// success path looks one up from the context (via WithSaveFunc) to let a
+// passing check still rewrite its expected value, e.g. when recording a
+// snapshot interactively; callers that don't support that (most tests) must
+// still attach a no-op, or StdCheckError has nothing to type-assert.
+type SaveFunc func(path string, value any) error
+
+type saveFuncKey struct{}
+
+// WithSaveFunc attaches fn to ctx under the key StdCheckError looks up.
+func WithSaveFunc(ctx context.Context, fn SaveFunc) context.Context {
+	return context.WithValue(ctx, saveFuncKey{}, fn)
+}
+
+// defaultSnapshotDir is where checkSnapshot reads and writes golden files
+// when a runner isn't configured with an explicit snapshot directory.
+const defaultSnapshotDir = "testdata/snapshots"
+
+// updateSnapshotsEnv mirrors Go's own `-update` testing convention: set it
+// to write the snapshot file instead of comparing against it.
+const updateSnapshotsEnv = "UPDATE_SNAPSHOTS"
+
+// checkSnapshot compares got against the golden file named name under dir,
+// supporting the same matcher placeholders (notnull, regex, ...) as an
+// inline table. The file is written instead of compared on its first run
+// (when it doesn't exist yet) or whenever UPDATE_SNAPSHOTS is set.
+func checkSnapshot(L *lua.LState, dir, name string, got map[string]any) error {
+	ctx := L.Context()
+	path := filepath.Join(dir, name)
+
+	gotJSON, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal response: %w", err)
+	}
+	gotJSON = append(gotJSON, '\n')
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("unable to read snapshot %q: %w", path, err)
+		}
+		return writeSnapshot(ctx, path, name, gotJSON, "Created snapshot %s")
+	}
+
+	if os.Getenv(updateSnapshotsEnv) != "" {
+		return writeSnapshot(ctx, path, name, gotJSON, "Updated snapshot %s")
+	}
+
+	wantTbl, err := jsonToLuaTable(L, want)
+	if err != nil {
+		return fmt.Errorf("unable to parse snapshot %q: %w", path, err)
+	}
+
+	if err := StdCheckError(ctx, wantTbl, got); err != nil {
+		diff := difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(want)),
+			B:        difflib.SplitLines(string(gotJSON)),
+			FromFile: name,
+			ToFile:   "actual",
+			Context:  3,
+		}
+		text, _ := difflib.GetUnifiedDiffString(diff)
+		Info(ctx, reporter.Info{
+			Type:     reporter.InfoTypeResponse,
+			Title:    fmt.Sprintf("Snapshot mismatch: %s", name),
+			Content:  text,
+			Language: "diff",
+		})
+		return err
+	}
+
+	return nil
+}
+
+// writeSnapshot (re)writes the golden file at path with gotJSON and reports
+// it under titleFmt, which must contain a single %s for name.
+func writeSnapshot(ctx context.Context, path, name string, gotJSON []byte, titleFmt string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("unable to create snapshot directory: %w", err)
+	}
+	if err := os.WriteFile(path, gotJSON, 0o644); err != nil {
+		return fmt.Errorf("unable to write snapshot %q: %w", path, err)
+	}
+
+	Info(ctx, reporter.Info{
+		Type:     reporter.InfoTypeResponse,
+		Title:    fmt.Sprintf(titleFmt, name),
+		Content:  string(gotJSON),
+		Language: "json",
+	})
+	return nil
+}
+
+// jsonToLuaTable parses data as JSON and converts it into a Lua table, so a
+// snapshot file (with its embedded matcher placeholders) can be checked the
+// same way as a table passed inline from a .lua spec.
+func jsonToLuaTable(L *lua.LState, data []byte) (*lua.LTable, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	tbl, ok := toLuaValue(L, v).(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("snapshot does not contain a JSON object")
+	}
+
+	return tbl, nil
+}
+
+func toLuaValue(L *lua.LState, v any) lua.LValue {
+	switch t := v.(type) {
+	case map[string]any:
+		tbl := L.NewTable()
+		for k, val := range t {
+			tbl.RawSetString(k, toLuaValue(L, val))
+		}
+		return tbl
+	case []any:
+		tbl := L.NewTable()
+		for i, val := range t {
+			tbl.RawSetInt(i+1, toLuaValue(L, val))
+		}
+		return tbl
+	case string:
+		if matched, ok := callMatcherPlaceholder(L, t); ok {
+			return matched
+		}
+		return lua.LString(t)
+	case float64:
+		return lua.LNumber(t)
+	case bool:
+		return lua.LBool(t)
+	default:
+		return lua.LNil
+	}
+}
+
+// matcherPlaceholder is the syntax a snapshot file uses to embed a matcher
+// (e.g. "$notnull", "$regex(^[0-9]+$)"), since plain JSON has no way to
+// express "call this Lua function" the way an inline table can (notnull(),
+// regex("...")).
+var matcherPlaceholder = regexp.MustCompile(`^\$(\w+)(?:\((.*)\))?$`)
+
+// callMatcherPlaceholder resolves a snapshot string matching
+// matcherPlaceholder by calling the same-named global Lua function — the
+// same notnull()/regex(...) function a .lua spec would call inline — so a
+// golden file can embed the existing matcher placeholders, and returns its
+// result. ok is false if s isn't placeholder syntax, or names something
+// that isn't a registered function, in which case the caller should fall
+// back to treating s as a literal string.
+func callMatcherPlaceholder(L *lua.LState, s string) (lua.LValue, bool) {
+	m := matcherPlaceholder.FindStringSubmatch(s)
+	if m == nil {
+		return nil, false
+	}
+
+	fn, ok := L.GetGlobal(m[1]).(*lua.LFunction)
+	if !ok {
+		return nil, false
+	}
+
+	var args []lua.LValue
+	if m[2] != "" {
+		args = append(args, lua.LString(m[2]))
+	}
+
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args...); err != nil {
+		return nil, false
+	}
+
+	ret := L.Get(-1)
+	L.Pop(1)
+	return ret, true
+}
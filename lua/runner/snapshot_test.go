@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestCheckSnapshotCreatesOnFirstRun(t *testing.T) {
+	dir := t.TempDir()
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	if err := checkSnapshot(L, dir, "user.json", map[string]any{"name": "alice"}); err != nil {
+		t.Fatalf("checkSnapshot: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "user.json"))
+	if err != nil {
+		t.Fatalf("expected snapshot to be created, got: %v", err)
+	}
+
+	want := "{\n  \"name\": \"alice\"\n}\n"
+	if string(got) != want {
+		t.Fatalf("got snapshot %q, want %q", got, want)
+	}
+}
+
+func TestCheckSnapshotComparesAgainstExisting(t *testing.T) {
+	dir := t.TempDir()
+	seed := []byte("{\n  \"name\": \"alice\"\n}\n")
+	if err := os.WriteFile(filepath.Join(dir, "user.json"), seed, 0o644); err != nil {
+		t.Fatalf("seed snapshot: %v", err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(WithSaveFunc(context.Background(), func(string, any) error { return nil }))
+
+	if err := checkSnapshot(L, dir, "user.json", map[string]any{"name": "alice"}); err != nil {
+		t.Fatalf("expected matching snapshot to pass, got: %v", err)
+	}
+
+	if err := checkSnapshot(L, dir, "user.json", map[string]any{"name": "bob"}); err == nil {
+		t.Fatal("expected mismatched snapshot to fail")
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "user.json"))
+	if err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	if string(got) != string(seed) {
+		t.Fatalf("expected a failed compare to leave the snapshot untouched, got %q", got)
+	}
+}
+
+func TestCheckSnapshotUpdatesWhenEnvSet(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.json"), []byte("{\n  \"name\": \"alice\"\n}\n"), 0o644); err != nil {
+		t.Fatalf("seed snapshot: %v", err)
+	}
+
+	t.Setenv(updateSnapshotsEnv, "1")
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	if err := checkSnapshot(L, dir, "user.json", map[string]any{"name": "bob"}); err != nil {
+		t.Fatalf("checkSnapshot: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "user.json"))
+	if err != nil {
+		t.Fatalf("read updated snapshot: %v", err)
+	}
+
+	want := "{\n  \"name\": \"bob\"\n}\n"
+	if string(got) != want {
+		t.Fatalf("expected snapshot to be overwritten, got %q, want %q", got, want)
+	}
+}
+
+func TestToLuaValueResolvesMatcherPlaceholders(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	notNullCalls := 0
+	L.SetGlobal("notnull", L.NewFunction(func(L *lua.LState) int {
+		notNullCalls++
+		ud := L.NewUserData()
+		ud.Value = "NOTNULL"
+		L.Push(ud)
+		return 1
+	}))
+
+	var regexArg string
+	L.SetGlobal("regex", L.NewFunction(func(L *lua.LState) int {
+		regexArg = L.CheckString(1)
+		ud := L.NewUserData()
+		ud.Value = "REGEX:" + regexArg
+		L.Push(ud)
+		return 1
+	}))
+
+	got := toLuaValue(L, "$notnull")
+	ud, ok := got.(*lua.LUserData)
+	if !ok || ud.Value != "NOTNULL" {
+		t.Fatalf("expected $notnull to resolve via the notnull() global, got %v", got)
+	}
+	if notNullCalls != 1 {
+		t.Fatalf("expected notnull() to be called once, got %d", notNullCalls)
+	}
+
+	got = toLuaValue(L, "$regex(^[0-9]+$)")
+	ud, ok = got.(*lua.LUserData)
+	if !ok || ud.Value != "REGEX:^[0-9]+$" {
+		t.Fatalf("expected $regex(...) to resolve via the regex() global, got %v", got)
+	}
+	if regexArg != "^[0-9]+$" {
+		t.Fatalf("expected regex() to be called with %q, got %q", "^[0-9]+$", regexArg)
+	}
+
+	if got := toLuaValue(L, "$unknownMatcher"); got != lua.LString("$unknownMatcher") {
+		t.Fatalf("expected an unregistered placeholder name to fall back to a literal string, got %v", got)
+	}
+
+	if got := toLuaValue(L, "plain string"); got != lua.LString("plain string") {
+		t.Fatalf("expected a non-placeholder string to pass through unchanged, got %v", got)
+	}
+}
@@ -0,0 +1,409 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nais/tester/lua/reporter"
+	"github.com/nais/tester/lua/spec"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const defaultExpectTimeout = 5 * time.Second
+
+// WS is the WebSocket factory counterpart to REST (see REST for the
+// factory/instance split rationale). It also owns the real listener, since
+// http.Handler alone cannot be dialed with a WebSocket upgrade; each
+// instance gets its own connection.
+type WS struct {
+	server *httptest.Server
+}
+
+var _ spec.Runner = (*WS)(nil)
+
+func NewWSRunner(handler http.Handler) *WS {
+	return &WS{server: httptest.NewServer(handler)}
+}
+
+func (w *WS) Name() string {
+	return "ws"
+}
+
+func (w *WS) Functions() []*spec.Function {
+	return w.NewInstance(context.Background()).(*WSInstance).Functions()
+}
+
+// NewInstance returns a fresh WS runner sharing this factory's listener,
+// with its own connection, so the spec engine can run independent .lua
+// files concurrently.
+func (w *WS) NewInstance(_ context.Context) spec.Runner {
+	return &WSInstance{factory: w}
+}
+
+// Close shuts down the listener and its serving goroutine. Call it once per
+// factory lifetime (not per instance), so hundreds of specs don't each
+// leak a socket.
+func (w *WS) Close() {
+	w.server.Close()
+}
+
+// WSInstance is the per-test WS runner handed out by WS.NewInstance.
+type WSInstance struct {
+	factory *WS
+	conn    *websocket.Conn
+}
+
+var _ spec.Runner = (*WSInstance)(nil)
+
+func (w *WSInstance) Name() string {
+	return w.factory.Name()
+}
+
+func (w *WSInstance) Functions() []*spec.Function {
+	return []*spec.Function{
+		{
+			Name: "connect",
+			Args: []spec.Argument{
+				{
+					Name: "path",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The path to connect to",
+				},
+			},
+			Doc:  "Open a WebSocket connection",
+			Func: w.connect,
+		},
+		{
+			Name: "send",
+			Args: []spec.Argument{
+				{
+					Name: "msg",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString, spec.ArgumentTypeTable},
+					Doc:  "The message to send",
+				},
+			},
+			Doc:  "Send a frame on the connection",
+			Func: w.send,
+		},
+		{
+			Name: "expect",
+			Args: []spec.Argument{
+				{
+					Name: "msg",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString, spec.ArgumentTypeTable},
+					Doc:  "The expected message",
+				},
+				{
+					Name: "timeout_ms?",
+					Type: []spec.ArgumentType{spec.ArgumentTypeNumber},
+					Doc:  "How long to wait for a frame, defaults to 5000ms",
+				},
+			},
+			Doc:  "Wait for and check the next received frame",
+			Func: w.expect,
+		},
+		{
+			Name: "close",
+			Doc:  "Close the connection",
+			Func: w.close,
+		},
+	}
+}
+
+func (w *WSInstance) connect(L *lua.LState) int {
+	path := L.CheckString(1)
+
+	url := "ws" + strings.TrimPrefix(w.factory.server.URL, "http") + path
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		L.RaiseError("ws.connect: unable to dial %s: %v", url, err)
+		return 0
+	}
+
+	w.conn = conn
+	return 0
+}
+
+func (w *WSInstance) send(L *lua.LState) int {
+	if w.conn == nil {
+		L.RaiseError("ws.send: connect not called")
+		return 0
+	}
+
+	var payload []byte
+	switch v := L.Get(1).(type) {
+	case lua.LString:
+		payload = []byte(v.String())
+	case *lua.LTable:
+		b, err := json.Marshal(luaTableToMap(v))
+		if err != nil {
+			L.RaiseError("ws.send: unable to marshal table: %v", err)
+			return 0
+		}
+		payload = b
+	}
+
+	Info(L.Context(), reporter.Info{
+		Type:     reporter.InfoTypeRequest,
+		Title:    "WebSocket Send",
+		Content:  string(payload),
+		Language: "text",
+	})
+
+	if err := w.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+		L.RaiseError("ws.send: %v", err)
+	}
+
+	return 0
+}
+
+func (w *WSInstance) expect(L *lua.LState) int {
+	if w.conn == nil {
+		L.RaiseError("ws.expect: connect not called")
+		return 0
+	}
+
+	timeout := defaultExpectTimeout
+	if L.GetTop() > 1 {
+		timeout = time.Duration(L.CheckInt(2)) * time.Millisecond
+	}
+
+	if err := w.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		L.RaiseError("ws.expect: %v", err)
+		return 0
+	}
+
+	_, frame, err := w.conn.ReadMessage()
+	if err != nil {
+		L.RaiseError("ws.expect: no frame received within %s: %v", timeout, err)
+		return 0
+	}
+
+	Info(L.Context(), reporter.Info{
+		Type:     reporter.InfoTypeResponse,
+		Title:    "WebSocket Frame",
+		Content:  string(frame),
+		Language: "json",
+	})
+
+	checkFrame(L, 1, frame)
+	return 0
+}
+
+func (w *WSInstance) close(L *lua.LState) int {
+	if w.conn == nil {
+		return 0
+	}
+
+	if err := w.conn.Close(); err != nil {
+		L.RaiseError("ws.close: %v", err)
+	}
+	w.conn = nil
+
+	return 0
+}
+
+// SSE is the Server-Sent-Events factory counterpart to REST (see REST for
+// the factory/instance split rationale); each instance gets its own
+// subscription.
+type SSE struct {
+	server *httptest.Server
+}
+
+var _ spec.Runner = (*SSE)(nil)
+
+func NewSSERunner(handler http.Handler) *SSE {
+	return &SSE{server: httptest.NewServer(handler)}
+}
+
+func (s *SSE) Name() string {
+	return "sse"
+}
+
+func (s *SSE) Functions() []*spec.Function {
+	return s.NewInstance(context.Background()).(*SSEInstance).Functions()
+}
+
+// NewInstance returns a fresh SSE runner sharing this factory's listener,
+// with its own subscription, so the spec engine can run independent .lua
+// files concurrently.
+func (s *SSE) NewInstance(_ context.Context) spec.Runner {
+	return &SSEInstance{factory: s}
+}
+
+// Close is WS.Close's SSE equivalent.
+func (s *SSE) Close() {
+	s.server.Close()
+}
+
+// SSEInstance is the per-test SSE runner handed out by SSE.NewInstance,
+// reading its own response body as a long-lived stream of
+// "event:"/"data:" frames.
+type SSEInstance struct {
+	factory *SSE
+	resp    *http.Response
+	reader  *bufio.Reader
+}
+
+var _ spec.Runner = (*SSEInstance)(nil)
+
+func (s *SSEInstance) Name() string {
+	return s.factory.Name()
+}
+
+func (s *SSEInstance) Functions() []*spec.Function {
+	return []*spec.Function{
+		{
+			Name: "subscribe",
+			Args: []spec.Argument{
+				{
+					Name: "path",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The path to subscribe to",
+				},
+			},
+			Doc:  "Open an SSE subscription",
+			Func: s.subscribe,
+		},
+		{
+			Name: "expectEvent",
+			Args: []spec.Argument{
+				{
+					Name: "name",
+					Type: []spec.ArgumentType{spec.ArgumentTypeString},
+					Doc:  "The expected event name",
+				},
+				{
+					Name: "data",
+					Type: []spec.ArgumentType{spec.ArgumentTypeTable},
+					Doc:  "The expected event data",
+				},
+			},
+			Doc:  "Wait for and check the next received event",
+			Func: s.expectEvent,
+		},
+		{
+			Name: "close",
+			Doc:  "Close the subscription",
+			Func: s.close,
+		},
+	}
+}
+
+func (s *SSEInstance) subscribe(L *lua.LState) int {
+	path := L.CheckString(1)
+
+	req, err := http.NewRequestWithContext(L.Context(), http.MethodGet, s.factory.server.URL+path, nil)
+	if err != nil {
+		L.RaiseError("sse.subscribe: %v", err)
+		return 0
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		L.RaiseError("sse.subscribe: %v", err)
+		return 0
+	}
+
+	s.resp = resp
+	s.reader = bufio.NewReader(resp.Body)
+
+	return 0
+}
+
+func (s *SSEInstance) expectEvent(L *lua.LState) int {
+	if s.reader == nil {
+		L.RaiseError("sse.expectEvent: subscribe not called")
+		return 0
+	}
+
+	name := L.CheckString(1)
+
+	event, data, err := s.readEvent()
+	if err != nil {
+		L.RaiseError("sse.expectEvent: %v", err)
+		return 0
+	}
+
+	Info(L.Context(), reporter.Info{
+		Type:     reporter.InfoTypeResponse,
+		Title:    fmt.Sprintf("SSE Event (%s)", event),
+		Content:  data,
+		Language: "json",
+	})
+
+	if event != name {
+		L.RaiseError("sse.expectEvent: expected event %q, got %q", name, event)
+		return 0
+	}
+
+	checkFrame(L, 2, []byte(data))
+	return 0
+}
+
+func (s *SSEInstance) close(L *lua.LState) int {
+	if s.resp == nil {
+		return 0
+	}
+
+	if err := s.resp.Body.Close(); err != nil {
+		L.RaiseError("sse.close: %v", err)
+	}
+	s.resp = nil
+	s.reader = nil
+
+	return 0
+}
+
+func (s *SSEInstance) readEvent() (string, string, error) {
+	var event string
+	var data strings.Builder
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if event != "" || data.Len() > 0 {
+				return event, data.String(), nil
+			}
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+}
+
+// checkFrame compares a received frame against a Lua value at stackIdx, which
+// may be either a table (checked with StdCheck) or a plain string.
+func checkFrame(L *lua.LState, stackIdx int, frame []byte) {
+	switch v := L.Get(stackIdx).(type) {
+	case *lua.LTable:
+		var res map[string]any
+		if err := json.Unmarshal(frame, &res); err != nil {
+			L.RaiseError("unable to unmarshal frame: %v", err)
+			return
+		}
+		StdCheck(L, v, res)
+	case lua.LString:
+		if string(v) != string(frame) {
+			L.RaiseError("expected frame %q, got %q", string(v), string(frame))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestWSConnectSendExpect(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("echo: %s", msg)))
+	})
+
+	factory := NewWSRunner(handler)
+	defer factory.Close()
+
+	instance := factory.NewInstance(context.Background()).(*WSInstance)
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	connectFn := L.NewFunction(instance.connect)
+	if err := L.CallByParam(lua.P{Fn: connectFn, NRet: 0, Protect: true}, lua.LString("/")); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer instance.conn.Close()
+
+	sendFn := L.NewFunction(instance.send)
+	if err := L.CallByParam(lua.P{Fn: sendFn, NRet: 0, Protect: true}, lua.LString("ping")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	expectFn := L.NewFunction(instance.expect)
+	if err := L.CallByParam(lua.P{Fn: expectFn, NRet: 0, Protect: true}, lua.LString("echo: ping")); err != nil {
+		t.Fatalf("expect: %v", err)
+	}
+}
+
+func TestWSExpectTimesOutWithNoFrame(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		<-r.Context().Done()
+	})
+
+	factory := NewWSRunner(handler)
+	defer factory.Close()
+
+	instance := factory.NewInstance(context.Background()).(*WSInstance)
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	connectFn := L.NewFunction(instance.connect)
+	if err := L.CallByParam(lua.P{Fn: connectFn, NRet: 0, Protect: true}, lua.LString("/")); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer instance.conn.Close()
+
+	expectFn := L.NewFunction(instance.expect)
+	err := L.CallByParam(lua.P{Fn: expectFn, NRet: 0, Protect: true}, lua.LString("anything"), lua.LNumber(50))
+	if err == nil {
+		t.Fatal("expected expect() to time out with no frame sent")
+	}
+}
+
+func TestSSESubscribeExpectEventAndClose(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: greeting\ndata: {\"message\":\"hi\"}\n\n")
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	})
+
+	factory := NewSSERunner(handler)
+	defer factory.Close()
+
+	instance := factory.NewInstance(context.Background()).(*SSEInstance)
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(context.Background())
+
+	subscribeFn := L.NewFunction(instance.subscribe)
+	if err := L.CallByParam(lua.P{Fn: subscribeFn, NRet: 0, Protect: true}, lua.LString("/events")); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	dataTbl := L.NewTable()
+	dataTbl.RawSetString("message", lua.LString("hi"))
+	expectFn := L.NewFunction(instance.expectEvent)
+	if err := L.CallByParam(lua.P{Fn: expectFn, NRet: 0, Protect: true}, lua.LString("greeting"), dataTbl); err != nil {
+		t.Fatalf("expectEvent: %v", err)
+	}
+
+	closeFn := L.NewFunction(instance.close)
+	if err := L.CallByParam(lua.P{Fn: closeFn, NRet: 0, Protect: true}); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if instance.resp != nil || instance.reader != nil {
+		t.Fatalf("expected close to clear resp/reader, got resp=%v reader=%v", instance.resp, instance.reader)
+	}
+}